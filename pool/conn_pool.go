@@ -1,40 +1,241 @@
 package pool
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/jackc/pgx"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type ConnPoolConfig struct {
 	pgx.ConnConfig
-	MaxConnections int                   // max simultaneous connections to use, default 5, must be at least 2
+	MaxConnections int                   // max simultaneous connections to use per target, default 5, must be at least 1
 	AfterConnect   func(*pgx.Conn) error // function to call on every new connection
+
+	// MaxConnLifetime, if non-zero, closes a connection once it has existed
+	// for at least this long, the next time it is acquired or the reaper
+	// checks it while idle. Release does not check it.
+	MaxConnLifetime time.Duration
+
+	// MaxConnIdleTime, if non-zero, closes a connection once it has sat idle
+	// in the pool for at least this long.
+	MaxConnIdleTime time.Duration
+
+	// HealthCheckPeriod, if non-zero, enables a background reaper that walks
+	// the pool on this interval, closing connections past MaxConnLifetime or
+	// MaxConnIdleTime and pinging the rest.
+	HealthCheckPeriod time.Duration
+
+	// AcquireTimeout, if non-zero, is the default used by Acquire (Acquire
+	// does not otherwise accept a context). AcquireContext callers control
+	// their own timeout via ctx and are unaffected by this setting.
+	AcquireTimeout time.Duration
+
+	// MinConnections, if non-zero, is the number of connections NewConnPool
+	// eagerly establishes up front per target, and the floor the pool
+	// asynchronously replenishes back to whenever Release or the reaper
+	// drops a target's live connections below it. It is capped at
+	// MaxConnections.
+	MinConnections int
+
+	// Targets, if non-empty, lists additional pgx.ConnConfig targets (e.g.
+	// read replicas) alongside the primary ConnConfig embedded above. Use
+	// AcquireRead/AcquireWrite -- or Query/Exec, which call them -- to route
+	// across targets via AcquirePolicy.
+	Targets []pgx.ConnConfig
+
+	// AcquirePolicy picks which target serves an acquire request. Defaults
+	// to PrimaryOnlyPolicy, so a pool with no extra Targets behaves exactly
+	// like a single-DSN pool.
+	AcquirePolicy AcquirePolicy
+
+	// FailoverCooldown is how long a target is skipped by AcquirePolicy
+	// after a failed dial or a read-only write rejection. Defaults to 30s.
+	FailoverCooldown time.Duration
+
+	// Observer, if set, is notified around every Acquire, Release, new
+	// connection, and Close, so callers can plug in OpenTelemetry,
+	// Prometheus, or similar without this package importing them.
+	Observer Observer
 }
 
-type ConnPool struct {
+// Observer lets a caller plug in external monitoring. conn is nil when the
+// acquire or the dial failed; only OnAcquire is given a wait duration, and
+// OnClose is given nothing. err is nil on success.
+type Observer interface {
+	OnAcquire(conn *pgx.Conn, wait time.Duration, err error)
+	OnRelease(conn *pgx.Conn)
+	OnConnect(conn *pgx.Conn, err error)
+	OnClose()
+}
+
+const (
+	replenishInitialBackoff = 100 * time.Millisecond
+	replenishMaxBackoff     = 30 * time.Second
+	defaultFailoverCooldown = 30 * time.Second
+)
+
+// connInfo tracks the lifecycle timestamps of a pooled connection alongside
+// the pgx.Conn itself. pgx.Conn has no notion of a pool, so this bookkeeping
+// lives in the pool instead.
+type connInfo struct {
+	createdAt  time.Time
+	lastUsedAt time.Time
+
+	// appliedPreparedOps is how far into p.preparedOps this connection has
+	// been brought up to date; see syncPreparedStatements.
+	appliedPreparedOps int
+}
+
+// preparedOp is one Prepare or Deallocate call recorded against the pool's
+// shared prepared statement cache, in the order applied. Connections that
+// are busy when the call is made replay their missed ops lazily, once they
+// are no longer in use; see syncPreparedStatements.
+type preparedOp struct {
+	name    string
+	sql     string // empty for a Deallocate
+	dealloc bool
+}
+
+// connRequest is a waiter's place in the FIFO queue for the next connection
+// that becomes available on a target. connChan is buffered 1 so Release can
+// hand off a connection without blocking on the waiter.
+type connRequest struct {
+	connChan chan *pgx.Conn
+}
+
+// poolTarget is one backend -- the primary or a replica -- that the pool can
+// route connections to. Its connection bookkeeping mirrors what a
+// single-target ConnPool tracked before multi-target support was added.
+type poolTarget struct {
+	config pgx.ConnConfig
+
+	primary      bool // best guess until primaryKnown; see detectPrimary and buildTargets
+	primaryKnown bool
+
+	unhealthyUntil time.Time // zero means healthy
+
 	allConnections       []*pgx.Conn
 	availableConnections []*pgx.Conn
-	cond                 *sync.Cond
-	config               pgx.ConnConfig // config used when establishing connection
-	maxConnections       int
-	afterConnect         func(*pgx.Conn) error
-	logger               pgx.Logger
-	logLevel             int
-	closed               bool
+	waiters              []*connRequest
+
+	// dialing counts createConnection calls in flight for this target.
+	// acquireOnce and replenish both release p.mux for the duration of the
+	// actual dial, so they reserve capacity here first to keep
+	// len(allConnections)+dialing from ever overshooting MaxConnections.
+	dialing int
+}
+
+func (t *poolTarget) unhealthy(now time.Time) bool {
+	return now.Before(t.unhealthyUntil)
+}
+
+// buildTargets builds the target list for NewConnPool: primary first, then
+// one poolTarget per extra config. primary defaults to true only for the
+// embedded ConnConfig -- the one a single-DSN pool has always connected to.
+// Extra targets start with primary/primaryKnown both false, so a configured
+// replica that hasn't been dialed yet (e.g. MinConnections == 0) is treated
+// as a replica candidate by ReadPreferredPolicy from the start, rather than
+// as an undetected primary until something happens to dial it.
+func buildTargets(primary pgx.ConnConfig, extra []pgx.ConnConfig) []*poolTarget {
+	targets := make([]*poolTarget, 0, 1+len(extra))
+	targets = append(targets, &poolTarget{config: primary, primary: true})
+	for _, cfg := range extra {
+		targets = append(targets, &poolTarget{config: cfg})
+	}
+	return targets
+}
+
+// nextWaiter pops the next waiter off the FIFO queue, or returns nil if there
+// are none. p.mux must be held.
+func (t *poolTarget) nextWaiter() *connRequest {
+	if len(t.waiters) == 0 {
+		return nil
+	}
+	req := t.waiters[0]
+	t.waiters = t.waiters[1:]
+	return req
+}
+
+// removeWaiter removes req from the waiter queue, if still present. p.mux
+// must be held.
+func (t *poolTarget) removeWaiter(req *connRequest) {
+	for i, w := range t.waiters {
+		if w == req {
+			t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type ConnPool struct {
+	// Accessed atomically; kept first in the struct for 64-bit alignment on
+	// 32-bit platforms. See https://pkg.go.dev/sync/atomic#pkg-note-BUG.
+	acquireCount         uint64
+	acquireDurationNanos uint64
+	emptyAcquireCount    uint64
+	canceledAcquireCount uint64
+	newConnCount         uint64
+	afterConnectErrors   uint64
+	closedMaxLifetime    uint64
+	closedMaxIdle        uint64
+
+	targets  []*poolTarget
+	policy   AcquirePolicy
+	observer Observer
+
+	connInfo           map[*pgx.Conn]*connInfo
+	connTarget         map[*pgx.Conn]int // which targets index owns each connection
+	preparedStatements map[string]string // name -> sql, applied to every current and future connection
+	preparedOps        []preparedOp      // history of Prepare/Deallocate calls, for lazily syncing busy connections
+
+	mux         sync.Mutex
+	stateChange chan struct{} // closed and replaced on every state change; lets Close wait without a cond
+
+	maxConnections int
+	afterConnect   func(*pgx.Conn) error
+	logger         pgx.Logger
+	logLevel       int
+	closed         bool
+
+	maxConnLifetime   time.Duration
+	maxConnIdleTime   time.Duration
+	healthCheckPeriod time.Duration
+	acquireTimeout    time.Duration
+	unhealthyCooldown time.Duration
+
+	minConnections int
+	replenishing   bool // true while a replenish goroutine is running; guards against starting a second one
+
+	reaperDone chan struct{} // closed by Close to tell the reaper to stop
+	reaperExit chan struct{} // closed by the reaper once it has stopped
 }
 
 type ConnPoolStat struct {
-	MaxConnections       int // max simultaneous connections to use
-	CurrentConnections   int // current live connections
-	AvailableConnections int // unused live connections
+	MaxConnections       int // max simultaneous connections to use per target
+	CurrentConnections   int // current live connections, summed across all targets
+	AvailableConnections int // unused live connections, summed across all targets
+
+	AcquireCount         uint64        // number of successful Acquire/AcquireRead/AcquireWrite calls
+	AcquireDuration      time.Duration // sum of time spent in successful Acquire calls; divide by AcquireCount for the mean
+	EmptyAcquireCount    uint64        // acquires that had to wait because their target had no available or spare connection
+	CanceledAcquireCount uint64        // acquires whose context was cancelled or timed out while waiting
+	NewConnCount         uint64        // connections dialed, across all targets
+	AfterConnectErrors   uint64        // AfterConnect invocations that returned an error
+
+	ClosedMaxLifetime uint64 // connections closed, on acquire or by the reaper, for exceeding MaxConnLifetime
+	ClosedMaxIdle     uint64 // connections closed, on acquire or by the reaper, for exceeding MaxConnIdleTime or failing a ping
 }
 
 // NewConnPool creates a new ConnPool. config.ConnConfig is passed through to
-// Connect directly.
+// Connect directly, as the primary target. config.Targets, if any, adds
+// further targets (e.g. read replicas) that AcquirePolicy can route to.
 func NewConnPool(config ConnPoolConfig) (p *ConnPool, err error) {
 	p = new(ConnPool)
-	p.config = config.ConnConfig
 	p.maxConnections = config.MaxConnections
 	if p.maxConnections == 0 {
 		p.maxConnections = 5
@@ -56,66 +257,441 @@ func NewConnPool(config ConnPoolConfig) (p *ConnPool, err error) {
 		p.logLevel = pgx.LogLevelNone
 	}
 
-	p.allConnections = make([]*pgx.Conn, 0, p.maxConnections)
-	p.availableConnections = make([]*pgx.Conn, 0, p.maxConnections)
-	p.cond = sync.NewCond(new(sync.Mutex))
+	p.targets = buildTargets(config.ConnConfig, config.Targets)
 
-	// Initially establish one connection
+	p.policy = config.AcquirePolicy
+	if p.policy == nil {
+		p.policy = PrimaryOnlyPolicy{}
+	}
+	p.unhealthyCooldown = config.FailoverCooldown
+	if p.unhealthyCooldown == 0 {
+		p.unhealthyCooldown = defaultFailoverCooldown
+	}
+	p.observer = config.Observer
+
+	p.connInfo = make(map[*pgx.Conn]*connInfo, p.maxConnections)
+	p.connTarget = make(map[*pgx.Conn]int, p.maxConnections)
+	p.preparedStatements = make(map[string]string)
+	p.stateChange = make(chan struct{})
+
+	p.maxConnLifetime = config.MaxConnLifetime
+	p.maxConnIdleTime = config.MaxConnIdleTime
+	p.healthCheckPeriod = config.HealthCheckPeriod
+	p.acquireTimeout = config.AcquireTimeout
+
+	p.minConnections = config.MinConnections
+	if p.minConnections > p.maxConnections {
+		p.minConnections = p.maxConnections
+	}
+
+	// Initially establish one connection on the primary target
 	var c *pgx.Conn
-	c, err = p.createConnection()
+	c, err = p.createConnection(0)
 	if err != nil {
 		return
 	}
-	p.allConnections = append(p.allConnections, c)
-	p.availableConnections = append(p.availableConnections, c)
+	p.targets[0].allConnections = append(p.targets[0].allConnections, c)
+	p.targets[0].availableConnections = append(p.targets[0].availableConnections, c)
+
+	// Eagerly warm every target up to MinConnections so callers after a
+	// restart or network blip don't pay connect cost on the first few
+	// requests.
+	for ti, t := range p.targets {
+		for len(t.allConnections) < p.minConnections {
+			c, err = p.createConnection(ti)
+			if err != nil {
+				// Leave the pool usable with whatever connections were
+				// established; the background replenisher keeps trying to
+				// reach MinConnections.
+				err = nil
+				break
+			}
+			t.allConnections = append(t.allConnections, c)
+			t.availableConnections = append(t.availableConnections, c)
+		}
+	}
+
+	if p.healthCheckPeriod > 0 {
+		p.reaperDone = make(chan struct{})
+		p.reaperExit = make(chan struct{})
+		go p.reaper()
+	}
+
+	p.mux.Lock()
+	p.maybeReplenish()
+	p.mux.Unlock()
 
 	return
 }
 
-// Acquire takes exclusive use of a connection until it is released.
-func (p *ConnPool) Acquire() (c *pgx.Conn, err error) {
-	p.cond.L.Lock()
-	defer p.cond.L.Unlock()
+// Acquire takes exclusive use of a write connection, routed as AcquireWrite
+// routes it, until it is released. It blocks until a connection is available and cannot be
+// cancelled; use AcquireContext to bound the wait. If config.AcquireTimeout
+// was set, Acquire gives up and returns its error after that long.
+func (p *ConnPool) Acquire() (*pgx.Conn, error) {
+	ctx := context.Background()
+	if p.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.acquireTimeout)
+		defer cancel()
+	}
+	return p.AcquireContext(ctx)
+}
+
+// AcquireContext takes exclusive use of a write connection, routed as
+// AcquireWrite routes it, until it is released. If ctx is cancelled or its deadline is exceeded
+// while waiting for a connection to become available, AcquireContext returns
+// ctx.Err().
+func (p *ConnPool) AcquireContext(ctx context.Context) (*pgx.Conn, error) {
+	return p.acquireIntent(ctx, IntentWrite)
+}
+
+// AcquireWrite acquires a connection suitable for writes -- IntentWrite,
+// which PrimaryOnlyPolicy and ReadPreferredPolicy route to the primary;
+// RoundRobinPolicy and LeastBusyPolicy ignore intent. Exec and Begin use this.
+func (p *ConnPool) AcquireWrite(ctx context.Context) (*pgx.Conn, error) {
+	return p.acquireIntent(ctx, IntentWrite)
+}
+
+// AcquireRead acquires a connection suitable for reads -- routed to a
+// replica when AcquirePolicy allows it. Query and QueryRow use this.
+func (p *ConnPool) AcquireRead(ctx context.Context) (*pgx.Conn, error) {
+	return p.acquireIntent(ctx, IntentRead)
+}
+
+// AcquireFunc acquires a write connection, passes it to f, and releases it
+// when f returns, regardless of whether f returns an error. It returns f's
+// error, or the error from acquiring the connection.
+func (p *ConnPool) AcquireFunc(ctx context.Context, f func(*pgx.Conn) error) error {
+	c, err := p.AcquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Release(c)
+
+	return f(c)
+}
+
+func (p *ConnPool) acquireIntent(ctx context.Context, intent AcquireIntent) (c *pgx.Conn, err error) {
+	start := time.Now()
+	for {
+		var retry bool
+		c, retry, err = p.acquireOnce(ctx, intent)
+		if err != nil {
+			if err == ctx.Err() {
+				atomic.AddUint64(&p.canceledAcquireCount, 1)
+			}
+			if p.observer != nil {
+				p.observer.OnAcquire(nil, time.Since(start), err)
+			}
+			return nil, err
+		}
+		if retry {
+			continue
+		}
+
+		if p.reapIfUnhealthy(c) {
+			continue
+		}
+
+		atomic.AddUint64(&p.acquireCount, 1)
+		wait := time.Since(start)
+		atomic.AddUint64(&p.acquireDurationNanos, uint64(wait))
+		if p.observer != nil {
+			p.observer.OnAcquire(c, wait, nil)
+		}
+		return c, nil
+	}
+}
+
+// acquireOnce runs one attempt at acquiring a connection for intent: pick a
+// target via AcquirePolicy, then take an available connection on it, create
+// a new one, or wait in its FIFO queue until ctx is done or Release hands one
+// off. retry is true when the attempt failed in a way the caller should
+// simply try again (e.g. the chosen target's dial failed and was marked
+// unhealthy), rather than treat it as a hard error.
+func (p *ConnPool) acquireOnce(ctx context.Context, intent AcquireIntent) (c *pgx.Conn, retry bool, err error) {
+	p.mux.Lock()
 
 	if p.closed {
-		return nil, errors.New("cannot acquire from closed pool")
+		p.mux.Unlock()
+		return nil, false, errors.New("cannot acquire from closed pool")
 	}
 
+	targetIdx, err := p.policy.PickTarget(ctx, p.targetStatusesLocked(), intent)
+	if err != nil {
+		p.mux.Unlock()
+		return nil, false, err
+	}
+	t := p.targets[targetIdx]
+
 	// A connection is available
-	if len(p.availableConnections) > 0 {
-		c = p.availableConnections[len(p.availableConnections)-1]
-		p.availableConnections = p.availableConnections[:len(p.availableConnections)-1]
-		return
+	if len(t.availableConnections) > 0 {
+		c = t.availableConnections[len(t.availableConnections)-1]
+		t.availableConnections = t.availableConnections[:len(t.availableConnections)-1]
+		p.mux.Unlock()
+		return c, false, nil
 	}
 
-	// No connections are available, but we can create more
-	if len(p.allConnections) < p.maxConnections {
-		c, err = p.createConnection()
-		if err != nil {
-			return
+	// No connections are available, but we can create more. Reserve the slot
+	// and release the lock before dialing -- createConnection makes a real
+	// network round trip, and must not hold up Acquire/Release/Stat on every
+	// other target while it does.
+	if len(t.allConnections)+t.dialing < p.maxConnections {
+		t.dialing++
+		p.mux.Unlock()
+
+		c, dialErr := p.createConnection(targetIdx)
+
+		p.mux.Lock()
+		t.dialing--
+		if dialErr != nil {
+			t.unhealthyUntil = time.Now().Add(p.unhealthyCooldown)
+			p.notifyStateChange()
+			p.mux.Unlock()
+			return nil, true, nil
+		}
+		t.allConnections = append(t.allConnections, c)
+		p.notifyStateChange()
+		p.mux.Unlock()
+		return c, false, nil
+	}
+
+	// All connections on this target are in use and we cannot create more.
+	// Join its FIFO queue of waiters and let Release hand us a connection
+	// directly.
+	if p.logLevel >= pgx.LogLevelWarn {
+		p.logger.Warn("All connections in pool are busy - waiting...")
+	}
+
+	req := &connRequest{connChan: make(chan *pgx.Conn, 1)}
+	t.waiters = append(t.waiters, req)
+	p.mux.Unlock()
+
+	atomic.AddUint64(&p.emptyAcquireCount, 1)
+
+	select {
+	case c = <-req.connChan:
+		return c, false, nil
+	case <-ctx.Done():
+		p.mux.Lock()
+		select {
+		case c = <-req.connChan:
+			// Release raced us and already handed off a connection.
+			// Give it back instead of leaking it.
+			p.mux.Unlock()
+			p.Release(c)
+		default:
+			t.removeWaiter(req)
+			p.mux.Unlock()
+		}
+		return nil, false, ctx.Err()
+	}
+}
+
+// targetStatusesLocked builds the view of each target that AcquirePolicy
+// sees. p.mux must be held.
+func (p *ConnPool) targetStatusesLocked() []TargetStatus {
+	now := time.Now()
+	statuses := make([]TargetStatus, len(p.targets))
+	for i, t := range p.targets {
+		statuses[i] = TargetStatus{
+			Primary:              t.primary,
+			Unhealthy:            t.unhealthy(now),
+			CurrentConnections:   len(t.allConnections),
+			AvailableConnections: len(t.availableConnections),
+		}
+	}
+	return statuses
+}
+
+// reapIfUnhealthy checks a connection that is about to be handed to a caller
+// against MaxConnLifetime/MaxConnIdleTime and, if it has been idle past
+// healthCheckPeriod, pings it. If the connection is stale or the ping fails,
+// it is closed and removed from the pool and reapIfUnhealthy returns true so
+// the caller retries with a fresh connection. acquireIntent calls this on
+// every connection acquireOnce returns, by whichever path -- popped off
+// availableConnections, freshly dialed, or handed off by a waiter -- so it
+// also doubles as the catch-up point for a connection that picked up a
+// Prepare/Deallocate while sitting in availableConnections (e.g. returned by
+// unreserveIdleConn or returnReapedConn) rather than while checked out.
+func (p *ConnPool) reapIfUnhealthy(c *pgx.Conn) bool {
+	p.mux.Lock()
+	ci := p.connInfo[c]
+	p.mux.Unlock()
+	if ci == nil {
+		return false
+	}
+
+	now := time.Now()
+	expiredLifetime := p.maxConnLifetime > 0 && now.Sub(ci.createdAt) > p.maxConnLifetime
+	expiredIdle := p.maxConnIdleTime > 0 && now.Sub(ci.lastUsedAt) > p.maxConnIdleTime
+
+	if !expiredLifetime && !expiredIdle && p.healthCheckPeriod > 0 && now.Sub(ci.lastUsedAt) > p.healthCheckPeriod {
+		if _, pingErr := c.Exec("select 1"); pingErr != nil {
+			expiredIdle = true
 		}
-		p.allConnections = append(p.allConnections, c)
+	}
+
+	// lastUsedAt is left alone here: Release sets it, so the reaper's own
+	// checks of an idle connection do not reset its idle time.
+	if !expiredLifetime && !expiredIdle {
+		p.syncPreparedStatements(c)
+		return false
+	}
+
+	p.removeConn(c)
+	c.Close()
+
+	if expiredLifetime {
+		atomic.AddUint64(&p.closedMaxLifetime, 1)
+	} else {
+		atomic.AddUint64(&p.closedMaxIdle, 1)
+	}
+
+	p.mux.Lock()
+	p.maybeReplenish()
+	p.mux.Unlock()
+
+	return true
+}
+
+// markUnhealthy puts c's target into its failover cooldown, e.g. after it
+// rejects a write as read-only (a stale primary during a failover).
+func (p *ConnPool) markUnhealthy(c *pgx.Conn) {
+	p.mux.Lock()
+	if targetIdx, ok := p.connTarget[c]; ok {
+		p.targets[targetIdx].unhealthyUntil = time.Now().Add(p.unhealthyCooldown)
+	}
+	p.mux.Unlock()
+}
+
+func isReadOnlyError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "read-only")
+}
+
+// maybeReplenish starts a background goroutine to redial connections back up
+// to MinConnections on any target that is short, if one isn't already
+// running. Callers must hold p.mux.
+func (p *ConnPool) maybeReplenish() {
+	if p.closed || p.replenishing || p.minConnections == 0 {
+		return
+	}
+	short := false
+	for _, t := range p.targets {
+		if len(t.allConnections) < p.minConnections {
+			short = true
+			break
+		}
+	}
+	if !short {
 		return
 	}
+	p.replenishing = true
+	go p.replenish()
+}
+
+// replenish dials new connections, with exponential backoff on failure, on
+// whichever target is short of MinConnections, until every target reaches
+// MinConnections (or MaxConnections, or is closed or in its failover
+// cooldown). It always runs in its own goroutine and releases p.mux for the
+// duration of each dial (createConnection does the same), so callers never
+// block on a reconnect.
+func (p *ConnPool) replenish() {
+	backoff := replenishInitialBackoff
+	for {
+		p.mux.Lock()
+		if p.closed {
+			p.replenishing = false
+			p.mux.Unlock()
+			return
+		}
+
+		targetIdx := -1
+		now := time.Now()
+		for i, t := range p.targets {
+			if len(t.allConnections)+t.dialing < p.minConnections && len(t.allConnections)+t.dialing < p.maxConnections && !t.unhealthy(now) {
+				targetIdx = i
+				break
+			}
+		}
+		if targetIdx == -1 {
+			p.replenishing = false
+			p.mux.Unlock()
+			return
+		}
+		t := p.targets[targetIdx]
+		t.dialing++
+		p.mux.Unlock()
 
-	// All connections are in use and we cannot create more
-	if len(p.availableConnections) == 0 {
-		if p.logLevel >= pgx.LogLevelWarn {
-			p.logger.Warn("All connections in pool are busy - waiting...")
+		c, err := p.createConnection(targetIdx)
+
+		p.mux.Lock()
+		t.dialing--
+		if err != nil {
+			t.unhealthyUntil = time.Now().Add(p.unhealthyCooldown)
+			p.notifyStateChange()
+			p.mux.Unlock()
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > replenishMaxBackoff {
+				backoff = replenishMaxBackoff
+			}
+			continue
 		}
-		for len(p.availableConnections) == 0 {
-			p.cond.Wait()
+
+		t.allConnections = append(t.allConnections, c)
+		if waiter := t.nextWaiter(); waiter != nil {
+			waiter.connChan <- c
+		} else {
+			t.availableConnections = append(t.availableConnections, c)
 		}
+		p.notifyStateChange()
+		p.mux.Unlock()
+
+		backoff = replenishInitialBackoff
 	}
+}
 
-	c = p.availableConnections[len(p.availableConnections)-1]
-	p.availableConnections = p.availableConnections[:len(p.availableConnections)-1]
+// removeConn deletes conn from its target's allConnections, and from
+// connInfo/connTarget. It does not close the connection or touch
+// availableConnections.
+func (p *ConnPool) removeConn(conn *pgx.Conn) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
 
-	return
+	targetIdx, ok := p.connTarget[conn]
+	delete(p.connInfo, conn)
+	delete(p.connTarget, conn)
+	if !ok {
+		return
+	}
+
+	t := p.targets[targetIdx]
+	ac := t.allConnections
+	for i, c := range ac {
+		if conn == c {
+			ac[i] = ac[len(ac)-1]
+			t.allConnections = ac[0 : len(ac)-1]
+			break
+		}
+	}
+}
+
+// notifyStateChange wakes any goroutine blocked on the current stateChange
+// channel (e.g. Close waiting for connections to be released). p.mux must be
+// held.
+func (p *ConnPool) notifyStateChange() {
+	close(p.stateChange)
+	p.stateChange = make(chan struct{})
 }
 
 // Release gives up use of a connection.
 func (p *ConnPool) Release(conn *pgx.Conn) {
+	if p.observer != nil {
+		p.observer.OnRelease(conn)
+	}
+
 	if conn.TxStatus != 'I' {
 		conn.Exec("rollback")
 	}
@@ -128,58 +704,128 @@ func (p *ConnPool) Release(conn *pgx.Conn) {
 	}
 	conn.notifications = nil
 
-	p.cond.L.Lock()
-
-	inConnPool := false
-	for _, c := range p.allConnections {
-		if conn == c {
-			inConnPool = true
-			break
-		}
-	}
+	p.mux.Lock()
 
+	targetIdx, inConnPool := p.connTarget[conn]
 	if !inConnPool {
+		p.mux.Unlock()
 		conn.Close()
-		p.cond.L.Unlock()
-		p.cond.Signal()
 		return
 	}
+	t := p.targets[targetIdx]
 
 	if conn.IsAlive() {
-		p.availableConnections = append(p.availableConnections, conn)
+		// Catch conn up on any Prepare/Deallocate calls it missed while
+		// checked out before anyone else can acquire it; pgx.Conn isn't
+		// safe for concurrent use, so this can't happen while conn is
+		// still someone else's to use. Loop rather than check once: a
+		// Prepare/Deallocate can land in the window while syncing is
+		// unlocked, and conn isn't back in availableConnections yet for it
+		// to pick up directly, so it must be re-checked under the same
+		// lock right before conn is handed to a waiter or made available.
+		for {
+			ci, ok := p.connInfo[conn]
+			if !ok || ci.appliedPreparedOps >= len(p.preparedOps) {
+				if ok {
+					ci.lastUsedAt = time.Now()
+				}
+				break
+			}
+			p.mux.Unlock()
+			p.syncPreparedStatements(conn)
+			p.mux.Lock()
+		}
+
+		if waiter := t.nextWaiter(); waiter != nil {
+			waiter.connChan <- conn
+			p.mux.Unlock()
+			return
+		}
+		t.availableConnections = append(t.availableConnections, conn)
 	} else {
-		ac := p.allConnections
+		ac := t.allConnections
 		for i, c := range ac {
 			if conn == c {
 				ac[i] = ac[len(ac)-1]
-				p.allConnections = ac[0 : len(ac)-1]
+				t.allConnections = ac[0 : len(ac)-1]
 				break
 			}
 		}
+		delete(p.connInfo, conn)
+		delete(p.connTarget, conn)
+		p.maybeReplenish()
 	}
-	p.cond.L.Unlock()
-	p.cond.Signal()
+	p.notifyStateChange()
+	p.mux.Unlock()
 }
 
 // Close ends the use of a connection pool. It prevents any new connections
 // from being acquired, waits until all acquired connections are released,
 // then closes all underlying connections.
 func (p *ConnPool) Close() {
-	p.cond.L.Lock()
-	defer p.cond.L.Unlock()
+	p.mux.Lock()
 
+	if p.closed {
+		// Already closed; reaperDone was closed and every connection torn
+		// down the first time around, so there's nothing left to do. Without
+		// this, a repeat Close would close(reaperDone) a second time and
+		// panic.
+		p.mux.Unlock()
+		return
+	}
 	p.closed = true
+	reaperDone := p.reaperDone
+	reaperExit := p.reaperExit
 
-	// Wait until all connections are released
-	if len(p.availableConnections) != len(p.allConnections) {
-		for len(p.availableConnections) != len(p.allConnections) {
-			p.cond.Wait()
+	// Wait until all connections are released and no dial is in flight. A
+	// dial reserves its slot in t.dialing and releases p.mux for the actual
+	// connect (see acquireOnce and replenish), so without waiting for it too,
+	// Close could tear everything down and return while a goroutine is still
+	// about to append a freshly dialed connection to allConnections --
+	// leaking it past Close and making it invisible to every other method.
+	for !p.allReleasedLocked() {
+		ch := p.stateChange
+		p.mux.Unlock()
+		<-ch
+		p.mux.Lock()
+	}
+	p.mux.Unlock()
+
+	// Stop the reaper, and wait for it to actually exit, before tearing down
+	// connections below. If a tick landed in the window between unlocking
+	// above and here, it would walk allConnections/availableConnections
+	// concurrently with the close loop and ping (or double-close) a
+	// connection this function is in the middle of closing.
+	if reaperDone != nil {
+		close(reaperDone)
+		<-reaperExit
+	}
+
+	p.mux.Lock()
+	for _, t := range p.targets {
+		for _, c := range t.allConnections {
+			_ = c.Close()
 		}
+		t.allConnections = nil
+		t.availableConnections = nil
+	}
+	p.mux.Unlock()
+
+	if p.observer != nil {
+		p.observer.OnClose()
 	}
+}
 
-	for _, c := range p.allConnections {
-		_ = c.Close()
+// allReleasedLocked reports whether every target has all of its connections
+// available (i.e. none are checked out) and has no dial in flight. p.mux
+// must be held.
+func (p *ConnPool) allReleasedLocked() bool {
+	for _, t := range p.targets {
+		if len(t.availableConnections) != len(t.allConnections) || t.dialing != 0 {
+			return false
+		}
 	}
+	return true
 }
 
 // Reset closes all open connections, but leaves the pool open. It is intended
@@ -189,53 +835,397 @@ func (p *ConnPool) Close() {
 // It is safe to reset a pool while connections are checked out. Those
 // connections will be closed when they are returned to the pool.
 func (p *ConnPool) Reset() {
-	p.cond.L.Lock()
-	defer p.cond.L.Unlock()
+	p.mux.Lock()
+	defer p.mux.Unlock()
 
-	p.allConnections = make([]*pgx.Conn, 0, p.maxConnections)
-	p.availableConnections = make([]*pgx.Conn, 0, p.maxConnections)
+	for _, t := range p.targets {
+		t.allConnections = make([]*pgx.Conn, 0, p.maxConnections)
+		t.availableConnections = make([]*pgx.Conn, 0, p.maxConnections)
+	}
+	p.connInfo = make(map[*pgx.Conn]*connInfo, p.maxConnections)
+	p.connTarget = make(map[*pgx.Conn]int, p.maxConnections)
 }
 
-// Stat returns connection pool statistics
+// Stat returns connection pool statistics, summed across all targets.
 func (p *ConnPool) Stat() (s ConnPoolStat) {
-	p.cond.L.Lock()
-	defer p.cond.L.Unlock()
+	p.mux.Lock()
+	defer p.mux.Unlock()
 
 	s.MaxConnections = p.maxConnections
-	s.CurrentConnections = len(p.allConnections)
-	s.AvailableConnections = len(p.availableConnections)
+	for _, t := range p.targets {
+		s.CurrentConnections += len(t.allConnections)
+		s.AvailableConnections += len(t.availableConnections)
+	}
+
+	s.AcquireCount = atomic.LoadUint64(&p.acquireCount)
+	s.AcquireDuration = time.Duration(atomic.LoadUint64(&p.acquireDurationNanos))
+	s.EmptyAcquireCount = atomic.LoadUint64(&p.emptyAcquireCount)
+	s.CanceledAcquireCount = atomic.LoadUint64(&p.canceledAcquireCount)
+	s.NewConnCount = atomic.LoadUint64(&p.newConnCount)
+	s.AfterConnectErrors = atomic.LoadUint64(&p.afterConnectErrors)
+	s.ClosedMaxLifetime = atomic.LoadUint64(&p.closedMaxLifetime)
+	s.ClosedMaxIdle = atomic.LoadUint64(&p.closedMaxIdle)
 	return
 }
 
-func (p *ConnPool) createConnection() (c *pgx.Conn, err error) {
-	c, err = pgx.Connect(p.config)
+// createConnection dials a new connection on the given target, detects
+// whether that target is the primary, applies the pool's prepared statement
+// cache, and runs AfterConnect. It only takes p.mux for the brief moments it
+// reads or writes shared pool state, not for the dial itself or any of the
+// other round trips -- callers must NOT hold p.mux across this call, and
+// (if creating is conditional on available capacity) must reserve their
+// slot via t.dialing first, since another goroutine can run createConnection
+// concurrently on the same target.
+func (p *ConnPool) createConnection(targetIdx int) (c *pgx.Conn, err error) {
+	p.mux.Lock()
+	t := p.targets[targetIdx]
+	cfg := t.config
+	primaryKnown := t.primaryKnown
+	prepared := make(map[string]string, len(p.preparedStatements))
+	for name, sql := range p.preparedStatements {
+		prepared[name] = sql
+	}
+	appliedOps := len(p.preparedOps)
+	p.mux.Unlock()
+
+	c, err = pgx.Connect(cfg)
 	if err != nil {
+		if p.observer != nil {
+			p.observer.OnConnect(nil, err)
+		}
 		return
 	}
+	atomic.AddUint64(&p.newConnCount, 1)
+
+	if !primaryKnown {
+		if primary, ok := p.detectPrimary(c); ok {
+			p.mux.Lock()
+			t.primary = primary
+			t.primaryKnown = true
+			p.mux.Unlock()
+		}
+	}
+
+	for name, sql := range prepared {
+		if _, err = c.Prepare(name, sql); err != nil {
+			if p.observer != nil {
+				p.observer.OnConnect(c, err)
+			}
+			return
+		}
+	}
 	if p.afterConnect != nil {
 		err = p.afterConnect(c)
 		if err != nil {
+			atomic.AddUint64(&p.afterConnectErrors, 1)
+			if p.observer != nil {
+				p.observer.OnConnect(c, err)
+			}
 			return
 		}
 	}
+
+	now := time.Now()
+	p.mux.Lock()
+	p.connInfo[c] = &connInfo{createdAt: now, lastUsedAt: now, appliedPreparedOps: appliedOps}
+	p.connTarget[c] = targetIdx
+	p.mux.Unlock()
+
+	if p.observer != nil {
+		p.observer.OnConnect(c, nil)
+	}
 	return
 }
 
-// Exec acquires a connection, delegates the call to that connection, and releases the connection
+// detectPrimary queries pg_is_in_recovery() on c and reports whether the
+// target is the primary (recovery false) or a replica (recovery true), and
+// ok if the query told us either way. It does not take p.mux -- like the
+// dial, the Prepare loop, and AfterConnect above, this is a live round trip,
+// so callers must write the result into the target's primary/primaryKnown
+// fields themselves, under lock. If the query fails -- e.g. no network yet,
+// or a server too old to have the function -- ok is false and the target
+// keeps its default from buildTargets (primary for the embedded ConnConfig,
+// replica for extra Targets), so a single-target pool still works without
+// ever successfully detecting.
+func (p *ConnPool) detectPrimary(c *pgx.Conn) (primary bool, ok bool) {
+	rows, err := c.Query("select pg_is_in_recovery()")
+	if err != nil {
+		return false, false
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var inRecovery bool
+		if err := rows.Scan(&inRecovery); err == nil {
+			return !inRecovery, true
+		}
+	}
+	return false, false
+}
+
+// Prepare creates a prepared statement on every idle connection currently in
+// the pool (across all targets) and records it so that future connections --
+// dialed later by createConnection, including ones dialed after Reset --
+// prepare it too. Connections that are currently checked out by
+// another goroutine are not touched directly (pgx.Conn is not safe for
+// concurrent use); they instead catch up via syncPreparedStatements the next
+// time they are Released or re-Acquired.
+func (p *ConnPool) Prepare(name, sql string) (*pgx.PreparedStatement, error) {
+	c, err := p.AcquireWrite(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer p.Release(c)
+
+	ps, err := c.Prepare(name, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	p.preparedStatements[name] = sql
+	p.preparedOps = append(p.preparedOps, preparedOp{name: name, sql: sql})
+	rev := len(p.preparedOps)
+	// c already has the statement prepared above, so it's caught up too --
+	// without this, Release would see it as behind and re-prepare it on the
+	// same connection.
+	if ci, ok := p.connInfo[c]; ok {
+		ci.appliedPreparedOps = rev
+	}
+	idle := p.reserveIdleConnsLocked()
+	p.mux.Unlock()
+
+	// Apply and return each connection as soon as it's done, rather than
+	// holding every idle connection in the pool hostage for the full batch --
+	// Acquire can reuse one the moment it's caught up.
+	for _, ic := range idle {
+		if _, err := ic.Prepare(name, sql); err != nil && p.logLevel >= pgx.LogLevelError {
+			p.logger.Error(fmt.Sprintf("failed to prepare statement %q on pooled connection: %v", name, err))
+		}
+		p.unreserveIdleConn(ic, rev)
+	}
+
+	return ps, nil
+}
+
+// Deallocate removes a prepared statement from the pool's cache and from
+// every idle connection currently in the pool (across all targets).
+// Connections that are checked out, or created afterward, catch up the same
+// way Prepare's callers do.
+func (p *ConnPool) Deallocate(name string) error {
+	p.mux.Lock()
+	delete(p.preparedStatements, name)
+	p.preparedOps = append(p.preparedOps, preparedOp{name: name, dealloc: true})
+	rev := len(p.preparedOps)
+	idle := p.reserveIdleConnsLocked()
+	p.mux.Unlock()
+
+	var firstErr error
+	for _, c := range idle {
+		if err := c.Deallocate(name); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		p.unreserveIdleConn(c, rev)
+	}
+
+	return firstErr
+}
+
+// reserveIdleConnsLocked pulls every idle connection, across all targets, out
+// of its target's availableConnections and returns them, so that Acquire
+// cannot hand one to a caller while Prepare/Deallocate is still applying a
+// change to it over the network, or while the reaper is pinging or closing
+// it -- without this, a caller could see a connection the pool believes is
+// caught up, but that hasn't actually been prepared/deallocated yet, or race
+// the reaper on the same *pgx.Conn. The connections remain in
+// allConnections, so Close's wait for "all released" still holds until
+// they're returned via unreserveIdleConn or returnReapedConn. p.mux must be
+// held.
+func (p *ConnPool) reserveIdleConnsLocked() []*pgx.Conn {
+	var idle []*pgx.Conn
+	for _, t := range p.targets {
+		idle = append(idle, t.availableConnections...)
+		t.availableConnections = nil
+	}
+	return idle
+}
+
+// unreserveIdleConn returns a connection previously pulled out by
+// reserveIdleConnsLocked to its target -- directly to a waiter if one is
+// queued, mirroring Release, or back to availableConnections -- and marks it
+// caught up through rev, the revision of p.preparedOps that was current when
+// it (and every other connection idle at that moment) was reserved. Passing
+// the revision captured at reserve time, rather than len(p.preparedOps) as
+// read here, matters: a concurrent Prepare/Deallocate may have appended
+// further ops since, which this connection was never actually touched with
+// and must still pick up later via syncPreparedStatements. If a concurrent
+// Reset dropped the connection from the pool while it was reserved, it's
+// closed instead, the same way Release closes a connection it no longer
+// recognizes.
+func (p *ConnPool) unreserveIdleConn(c *pgx.Conn, rev int) {
+	p.mux.Lock()
+
+	if ci, ok := p.connInfo[c]; ok {
+		ci.appliedPreparedOps = rev
+	}
+	targetIdx, ok := p.connTarget[c]
+	if !ok {
+		p.mux.Unlock()
+		c.Close()
+		return
+	}
+	t := p.targets[targetIdx]
+	if waiter := t.nextWaiter(); waiter != nil {
+		waiter.connChan <- c
+		p.mux.Unlock()
+		return
+	}
+	t.availableConnections = append(t.availableConnections, c)
+	p.notifyStateChange()
+	p.mux.Unlock()
+}
+
+// syncPreparedStatements brings c up to date with every Prepare/Deallocate
+// call recorded against the pool since c was created or last synced. It is
+// called when a connection that may have missed some of those calls while
+// checked out becomes available again, so that a caller never sees a
+// connection silently missing a statement the pool believes is cached
+// everywhere.
+func (p *ConnPool) syncPreparedStatements(c *pgx.Conn) {
+	p.mux.Lock()
+	ci, ok := p.connInfo[c]
+	if !ok || ci.appliedPreparedOps >= len(p.preparedOps) {
+		p.mux.Unlock()
+		return
+	}
+	ops := append([]preparedOp(nil), p.preparedOps[ci.appliedPreparedOps:]...)
+	ci.appliedPreparedOps = len(p.preparedOps)
+	p.mux.Unlock()
+
+	for _, op := range ops {
+		var err error
+		if op.dealloc {
+			err = c.Deallocate(op.name)
+		} else {
+			_, err = c.Prepare(op.name, op.sql)
+		}
+		if err != nil && p.logLevel >= pgx.LogLevelError {
+			p.logger.Error(fmt.Sprintf("failed to sync prepared statement %q on pooled connection: %v", op.name, err))
+		}
+	}
+}
+
+// reaper periodically walks the idle connections in the pool, closing any
+// that have exceeded MaxConnLifetime or MaxConnIdleTime and pinging the rest,
+// until Close signals p.reaperDone.
+func (p *ConnPool) reaper() {
+	defer close(p.reaperExit)
+
+	ticker := time.NewTicker(p.healthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.reaperDone:
+			return
+		case <-ticker.C:
+			p.reapIdleConnections()
+		}
+	}
+}
+
+// reapIdleConnections closes idle connections, on any target, that are past
+// MaxConnLifetime or MaxConnIdleTime, and pings the rest so dead connections
+// (e.g. behind a NAT idle timeout) are replaced before a caller ever sees
+// them. Every candidate is pulled out of availableConnections first, the
+// same way reserveIdleConnsLocked does for Prepare/Deallocate -- otherwise a
+// concurrent Acquire could pop the same connection while this is pinging or
+// closing it, running concurrently on a *pgx.Conn that isn't safe for that,
+// or be handed a connection reapIfUnhealthy has already closed.
+func (p *ConnPool) reapIdleConnections() {
+	p.mux.Lock()
+	idle := p.reserveIdleConnsLocked()
+	p.mux.Unlock()
+
+	for _, c := range idle {
+		if p.reapIfUnhealthy(c) {
+			continue
+		}
+		p.returnReapedConn(c)
+	}
+}
+
+// returnReapedConn returns a connection previously pulled out of its
+// target's availableConnections by reserveIdleConnsLocked, once the reaper
+// has decided it's still healthy -- directly to a waiter if one is queued,
+// mirroring Release, or back to availableConnections. If a concurrent Reset
+// dropped the connection from the pool while it was reserved, it's closed
+// instead, the same way unreserveIdleConn is.
+func (p *ConnPool) returnReapedConn(c *pgx.Conn) {
+	p.mux.Lock()
+	targetIdx, ok := p.connTarget[c]
+	if !ok {
+		p.mux.Unlock()
+		c.Close()
+		return
+	}
+	t := p.targets[targetIdx]
+	if waiter := t.nextWaiter(); waiter != nil {
+		waiter.connChan <- c
+		p.mux.Unlock()
+		return
+	}
+	t.availableConnections = append(t.availableConnections, c)
+	p.notifyStateChange()
+	p.mux.Unlock()
+}
+
+// Exec acquires a write connection, delegates the call to that connection,
+// and releases the connection.
 func (p *ConnPool) Exec(sql string, arguments ...interface{}) (commandTag pgx.CommandTag, err error) {
-	var c *pgx.Conn
-	if c, err = p.Acquire(); err != nil {
+	return p.ExecContext(context.Background(), sql, arguments...)
+}
+
+// ExecContext acquires a write connection respecting ctx, delegates the call
+// to that connection, and releases the connection. If the target rejects the
+// write as read-only -- e.g. a stale primary mid-failover -- the target is
+// marked unhealthy for its cooldown and the exec is retried once against
+// whatever AcquirePolicy picks next.
+func (p *ConnPool) ExecContext(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgx.CommandTag, err error) {
+	c, err := p.AcquireWrite(ctx)
+	if err != nil {
 		return
 	}
-	defer p.Release(c)
 
-	return c.Exec(sql, arguments...)
+	commandTag, err = c.Exec(sql, arguments...)
+	if isReadOnlyError(err) {
+		p.markUnhealthy(c)
+		p.Release(c)
+
+		c, err = p.AcquireWrite(ctx)
+		if err != nil {
+			return
+		}
+		defer p.Release(c)
+		return c.Exec(sql, arguments...)
+	}
+
+	p.Release(c)
+	return
 }
 
-// Query acquires a connection and delegates the call to that connection. When
-// *Rows are closed, the connection is released automatically.
+// Query acquires a read connection and delegates the call to that
+// connection. When *Rows are closed, the connection is released
+// automatically.
 func (p *ConnPool) Query(sql string, args ...interface{}) (pgx.Rows, error) {
-	c, err := p.Acquire()
+	return p.QueryContext(context.Background(), sql, args...)
+}
+
+// QueryContext acquires a read connection respecting ctx and delegates the
+// call to that connection. When *Rows are closed, the connection is released
+// automatically.
+func (p *ConnPool) QueryContext(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	c, err := p.AcquireRead(ctx)
 	if err != nil {
 		// Because checking for errors can be deferred to the *rows, build one with the error
 		return pgx.rows{closed: true, err: err}, err
@@ -251,26 +1241,40 @@ func (p *ConnPool) Query(sql string, args ...interface{}) (pgx.Rows, error) {
 	return rows, nil
 }
 
-// QueryRow acquires a connection and delegates the call to that connection. The
-// connection is released automatically after Scan is called on the returned
-// *Row.
+// QueryRow acquires a read connection and delegates the call to that
+// connection. The connection is released automatically after Scan is called
+// on the returned *Row.
 func (p *ConnPool) QueryRow(sql string, args ...interface{}) *pgx.Row {
 	rows, _ := p.Query(sql, args...)
 	return (*pgx.Row)(rows)
 }
 
-// Begin acquires a connection and begins a transaction on it. When the
+// Begin acquires a write connection and begins a transaction on it. When the
 // transaction is closed the connection will be automatically released.
 func (p *ConnPool) Begin() (*pgx.Tx, error) {
 	return p.BeginIso("")
 }
 
-// BeginIso acquires a connection and begins a transaction in isolation mode iso
-// on it. When the transaction is closed the connection will be automatically
-// released.
+// BeginContext acquires a write connection respecting ctx and begins a
+// transaction on it. When the transaction is closed the connection will be
+// automatically released.
+func (p *ConnPool) BeginContext(ctx context.Context) (*pgx.Tx, error) {
+	return p.BeginIsoContext(ctx, "")
+}
+
+// BeginIso acquires a write connection and begins a transaction in isolation
+// mode iso on it. When the transaction is closed the connection will be
+// automatically released.
 func (p *ConnPool) BeginIso(iso string) (*pgx.Tx, error) {
+	return p.BeginIsoContext(context.Background(), iso)
+}
+
+// BeginIsoContext acquires a write connection respecting ctx and begins a
+// transaction in isolation mode iso on it. When the transaction is closed the
+// connection will be automatically released.
+func (p *ConnPool) BeginIsoContext(ctx context.Context, iso string) (*pgx.Tx, error) {
 	for {
-		c, err := p.Acquire()
+		c, err := p.AcquireWrite(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -294,4 +1298,4 @@ func (p *ConnPool) BeginIso(iso string) (*pgx.Tx, error) {
 		tx.pool = p
 		return tx, nil
 	}
-}
\ No newline at end of file
+}
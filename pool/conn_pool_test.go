@@ -0,0 +1,321 @@
+package pool
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// testConnConfig builds the primary target's ConnConfig from the environment
+// (mirroring pgx's own test suite) and skips the test if no database is
+// reachable, rather than failing CI environments that don't have one wired
+// up.
+func testConnConfig(t *testing.T) pgx.ConnConfig {
+	t.Helper()
+
+	cfg := pgx.ConnConfig{
+		Host:     envOr("PGX_TEST_HOST", "localhost"),
+		Database: envOr("PGX_TEST_DATABASE", "pgx_test"),
+		User:     envOr("PGX_TEST_USER", "pgx_test"),
+		Password: os.Getenv("PGX_TEST_PASSWORD"),
+	}
+
+	c, err := pgx.Connect(cfg)
+	if err != nil {
+		t.Skipf("no test database reachable, skipping: %v", err)
+	}
+	c.Close()
+
+	return cfg
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// TestPrepareDoesNotTouchBusyConnection guards against reaching into a
+// connection that's currently checked out by another goroutine: Prepare
+// should apply directly only to idle connections, and the connection held by
+// the caller here should pick up the statement the next time it is released
+// rather than racing with whatever it's doing right now.
+func TestPrepareDoesNotTouchBusyConnection(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	p, err := NewConnPool(ConnPoolConfig{ConnConfig: cfg, MaxConnections: 2})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+	defer p.Close()
+
+	held, err := p.AcquireWrite(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireWrite: %v", err)
+	}
+
+	if _, err := p.Prepare("test_prepare_busy", "select 1"); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	// held was busy throughout Prepare, so it must not have been touched
+	// directly; it should still be perfectly usable.
+	if _, err := held.Exec("select 1"); err != nil {
+		t.Fatalf("held connection unusable after Prepare: %v", err)
+	}
+
+	p.Release(held)
+
+	// Now that held is back in the pool, it should have caught up on the
+	// statement lazily.
+	c, err := p.AcquireWrite(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireWrite: %v", err)
+	}
+	defer p.Release(c)
+
+	if _, err := c.Exec("test_prepare_busy"); err != nil {
+		t.Errorf("statement not applied to previously-busy connection after Release: %v", err)
+	}
+}
+
+// TestCloseStopsReaperBeforeTeardown guards against the reaper racing
+// Close's teardown loop: with a very short HealthCheckPeriod, a tick landing
+// while Close is closing connections must not see (and double-close) one
+// that's already closed.
+func TestCloseStopsReaperBeforeTeardown(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	p, err := NewConnPool(ConnPoolConfig{
+		ConnConfig:        cfg,
+		MaxConnections:    2,
+		HealthCheckPeriod: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+
+	c, err := p.AcquireWrite(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireWrite: %v", err)
+	}
+	p.Release(c)
+
+	// Give the reaper a chance to be mid-tick right as Close runs.
+	time.Sleep(5 * time.Millisecond)
+	p.Close()
+}
+
+// TestCloseIsIdempotent guards against a repeat Close panicking on
+// close(reaperDone) a second time: calling Close twice, on a pool with the
+// reaper enabled, must be a harmless no-op the second time.
+func TestCloseIsIdempotent(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	p, err := NewConnPool(ConnPoolConfig{
+		ConnConfig:        cfg,
+		MaxConnections:    2,
+		HealthCheckPeriod: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+
+	p.Close()
+	p.Close()
+}
+
+// TestAcquireContextCancelWhileWaitingUnblocksPromptly guards the core
+// mechanism context-aware Acquire added: a waiter blocked on an exhausted
+// pool must unblock as soon as its context is cancelled, with ctx.Err(), and
+// must not leak its entry in the target's waiter queue.
+func TestAcquireContextCancelWhileWaitingUnblocksPromptly(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	p, err := NewConnPool(ConnPoolConfig{ConnConfig: cfg, MaxConnections: 1})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+	defer p.Close()
+
+	held, err := p.AcquireWrite(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireWrite: %v", err)
+	}
+	defer p.Release(held)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.AcquireContext(ctx)
+		done <- err
+	}()
+
+	// Give the goroutine a chance to actually join the waiter queue before
+	// cancelling, so this exercises the wait, not a pre-cancelled context.
+	time.Sleep(10 * time.Millisecond)
+
+	p.mux.Lock()
+	if len(p.targets[0].waiters) != 1 {
+		p.mux.Unlock()
+		t.Fatalf("waiter queue has %d entries, want 1 before cancel", len(p.targets[0].waiters))
+	}
+	p.mux.Unlock()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("AcquireContext returned %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AcquireContext did not unblock within a second of cancelling its context")
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if len(p.targets[0].waiters) != 0 {
+		t.Errorf("waiter queue has %d entries, want 0 after cancel (leaked waiter)", len(p.targets[0].waiters))
+	}
+}
+
+// TestAcquireContextCancelRacesReleaseHandoff guards the branch in
+// acquireOnce where ctx expires around the same moment Release hands a
+// waiter its connection off: whichever side wins, the connection must end up
+// back in the pool -- never leaked -- and AcquireContext must still resolve
+// promptly either way.
+func TestAcquireContextCancelRacesReleaseHandoff(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	p, err := NewConnPool(ConnPoolConfig{ConnConfig: cfg, MaxConnections: 1})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 20; i++ {
+		held, err := p.AcquireWrite(context.Background())
+		if err != nil {
+			t.Fatalf("AcquireWrite: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			c, err := p.AcquireContext(ctx)
+			if err == nil {
+				p.Release(c)
+			}
+			done <- err
+		}()
+
+		// Give the goroutine a moment to reach the waiter queue, then fire
+		// the cancel and the release as close together as possible.
+		time.Sleep(time.Millisecond)
+		go cancel()
+		p.Release(held)
+
+		select {
+		case err := <-done:
+			if err != nil && err != context.Canceled {
+				t.Fatalf("iteration %d: AcquireContext returned %v, want nil or context.Canceled", i, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("iteration %d: AcquireContext did not resolve within a second of the race", i)
+		}
+
+		p.mux.Lock()
+		waiters := len(p.targets[0].waiters)
+		available := len(p.targets[0].availableConnections)
+		p.mux.Unlock()
+		if waiters != 0 {
+			t.Fatalf("iteration %d: waiter queue has %d entries, want 0", i, waiters)
+		}
+		if available != 1 {
+			t.Fatalf("iteration %d: %d available connections, want 1 (held connection must end up back in the pool either way)", i, available)
+		}
+	}
+}
+
+// TestReplenishDoesNotBlockAcquire guards against replenish holding p.mux
+// across a dial. NewConnPool warms every target up to MinConnections before
+// it ever returns, so a pool that starts fully warmed never actually runs
+// replenish; instead, this adds a second target, after construction, that
+// can never finish dialing -- a raw listener that accepts the TCP connection
+// but never speaks the wire protocol -- and leaves only it short of
+// MinConnections. That forces replenish to genuinely be blocked inside
+// createConnection for the whole test, so an ordinary Acquire against the
+// (already-warmed, policy-preferred) primary target proves it isn't also
+// blocked on p.mux.
+func TestReplenishDoesNotBlockAcquire(t *testing.T) {
+	cfg := testConnConfig(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			accepted <- c
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	p, err := NewConnPool(ConnPoolConfig{ConnConfig: cfg, MaxConnections: 2})
+	if err != nil {
+		t.Fatalf("NewConnPool: %v", err)
+	}
+	defer p.Close()
+
+	// Add the decoy target and mark it (and only it, since the primary is
+	// already warmed) short of MinConnections, then kick off replenish
+	// directly -- reaching into unexported state is the only way to get a
+	// target into this state without NewConnPool's own warm-up loop blocking
+	// on it first.
+	p.mux.Lock()
+	p.targets = append(p.targets, &poolTarget{config: pgx.ConnConfig{Host: addr.IP.String(), Port: uint16(addr.Port)}})
+	p.minConnections = 1
+	p.maybeReplenish()
+	p.mux.Unlock()
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("replenish never dialed the decoy target")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		c, err := p.AcquireContext(ctx)
+		if err != nil {
+			done <- err
+			return
+		}
+		p.Release(c)
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Acquire while replenish was blocked mid-dial: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire blocked for over a second while replenish was mid-dial on another target")
+	}
+}
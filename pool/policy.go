@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// AcquireIntent describes what a connection will be used for, so a policy
+// can route reads to replicas while keeping writes on the primary.
+type AcquireIntent int
+
+const (
+	IntentWrite AcquireIntent = iota
+	IntentRead
+)
+
+// TargetStatus is what an AcquirePolicy sees about one configured target
+// (the primary or a replica) when deciding where to route an acquire
+// request.
+type TargetStatus struct {
+	Primary              bool // true once pg_is_in_recovery() has been observed false on this target; the embedded ConnConfig target starts out true before detection
+	Unhealthy            bool // true while the target is in its post-failure cooldown
+	CurrentConnections   int
+	AvailableConnections int
+}
+
+// AcquirePolicy picks which configured target -- by index into the slice
+// passed to PickTarget, in the same order as ConnPoolConfig.ConnConfig
+// followed by ConnPoolConfig.Targets -- should serve an acquire request.
+type AcquirePolicy interface {
+	PickTarget(ctx context.Context, targets []TargetStatus, intent AcquireIntent) (int, error)
+}
+
+// errNoHealthyTarget is returned by the built-in policies when every target
+// is unhealthy (or, for PrimaryOnlyPolicy, when no primary has been
+// identified).
+var errNoHealthyTarget = errors.New("pool: no healthy target available")
+
+// RoundRobinPolicy cycles through every healthy target, ignoring intent.
+type RoundRobinPolicy struct {
+	next uint64
+}
+
+func (p *RoundRobinPolicy) PickTarget(ctx context.Context, targets []TargetStatus, intent AcquireIntent) (int, error) {
+	candidates := healthyTargets(targets)
+	if len(candidates) == 0 {
+		return 0, errNoHealthyTarget
+	}
+	i := atomic.AddUint64(&p.next, 1) - 1
+	return candidates[int(i)%len(candidates)], nil
+}
+
+// LeastBusyPolicy routes to the healthy target with the fewest checked-out
+// connections, ignoring intent.
+type LeastBusyPolicy struct{}
+
+func (LeastBusyPolicy) PickTarget(ctx context.Context, targets []TargetStatus, intent AcquireIntent) (int, error) {
+	best := -1
+	bestBusy := 0
+	for i, t := range targets {
+		if t.Unhealthy {
+			continue
+		}
+		busy := t.CurrentConnections - t.AvailableConnections
+		if best == -1 || busy < bestBusy {
+			best = i
+			bestBusy = busy
+		}
+	}
+	if best == -1 {
+		return 0, errNoHealthyTarget
+	}
+	return best, nil
+}
+
+// PrimaryOnlyPolicy always routes to the primary, regardless of intent. It
+// is the default, so a pool with no extra Targets behaves exactly like a
+// single-DSN pool.
+type PrimaryOnlyPolicy struct{}
+
+func (PrimaryOnlyPolicy) PickTarget(ctx context.Context, targets []TargetStatus, intent AcquireIntent) (int, error) {
+	for i, t := range targets {
+		if t.Primary && !t.Unhealthy {
+			return i, nil
+		}
+	}
+	return 0, errNoHealthyTarget
+}
+
+// ReadPreferredPolicy routes writes to the primary. Reads go to a healthy
+// replica, round-robin, falling back to the primary if no replica is
+// healthy.
+type ReadPreferredPolicy struct {
+	next uint64
+}
+
+func (p *ReadPreferredPolicy) PickTarget(ctx context.Context, targets []TargetStatus, intent AcquireIntent) (int, error) {
+	if intent == IntentWrite {
+		return PrimaryOnlyPolicy{}.PickTarget(ctx, targets, intent)
+	}
+
+	var replicas []int
+	for i, t := range targets {
+		if !t.Primary && !t.Unhealthy {
+			replicas = append(replicas, i)
+		}
+	}
+	if len(replicas) == 0 {
+		return PrimaryOnlyPolicy{}.PickTarget(ctx, targets, intent)
+	}
+
+	i := atomic.AddUint64(&p.next, 1) - 1
+	return replicas[int(i)%len(replicas)], nil
+}
+
+func healthyTargets(targets []TargetStatus) []int {
+	var idx []int
+	for i, t := range targets {
+		if !t.Unhealthy {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
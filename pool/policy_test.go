@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx"
+)
+
+func TestBuildTargetsOnlyPrimaryDefaultsPrimary(t *testing.T) {
+	targets := buildTargets(pgx.ConnConfig{}, []pgx.ConnConfig{{}, {}})
+
+	if len(targets) != 3 {
+		t.Fatalf("got %d targets, want 3", len(targets))
+	}
+	if !targets[0].primary {
+		t.Error("primary target should default to primary=true")
+	}
+	for i, rep := range targets[1:] {
+		if rep.primary || rep.primaryKnown {
+			t.Errorf("target %d: got primary=%v primaryKnown=%v, want both false until dialed", i+1, rep.primary, rep.primaryKnown)
+		}
+	}
+}
+
+func TestReadPreferredPolicyRoutesToUndetectedReplica(t *testing.T) {
+	p := &ReadPreferredPolicy{}
+
+	// A replica target that has never been dialed (primary=false,
+	// primaryKnown=false, the buildTargets default) must still be picked
+	// over falling back to the primary -- otherwise AcquireRead never
+	// routes to a replica until something else happens to dial it first.
+	targets := []TargetStatus{
+		{Primary: true},  // index 0: primary
+		{Primary: false}, // index 1: undialed replica
+	}
+
+	idx, err := p.PickTarget(context.Background(), targets, IntentRead)
+	if err != nil {
+		t.Fatalf("PickTarget returned error: %v", err)
+	}
+	if idx != 1 {
+		t.Errorf("PickTarget returned %d, want 1 (the replica)", idx)
+	}
+}
+
+func TestLeastBusyPolicyPrefersFewerCheckedOutConnections(t *testing.T) {
+	p := LeastBusyPolicy{}
+
+	// Target 0 has more established connections overall, but they're all
+	// idle; target 1 has fewer established connections, but both are
+	// checked out. LeastBusyPolicy must route to target 0 -- the one with
+	// fewer busy connections, not the one with fewer total connections.
+	targets := []TargetStatus{
+		{CurrentConnections: 5, AvailableConnections: 5}, // 0 busy
+		{CurrentConnections: 2, AvailableConnections: 0}, // 2 busy
+	}
+
+	idx, err := p.PickTarget(context.Background(), targets, IntentWrite)
+	if err != nil {
+		t.Fatalf("PickTarget returned error: %v", err)
+	}
+	if idx != 0 {
+		t.Errorf("PickTarget returned %d, want 0 (fewer busy connections)", idx)
+	}
+}
+
+func TestReadPreferredPolicyWriteAlwaysPrimary(t *testing.T) {
+	p := &ReadPreferredPolicy{}
+
+	targets := []TargetStatus{
+		{Primary: true},
+		{Primary: false},
+	}
+
+	idx, err := p.PickTarget(context.Background(), targets, IntentWrite)
+	if err != nil {
+		t.Fatalf("PickTarget returned error: %v", err)
+	}
+	if idx != 0 {
+		t.Errorf("PickTarget returned %d, want 0 (the primary)", idx)
+	}
+}